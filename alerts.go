@@ -0,0 +1,353 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// RuleKind selects which condition an AlertRule evaluates.
+type RuleKind string
+
+const (
+	// RuleRateAbove fires when a device's transmit/receive rate stays
+	// above Threshold (bytes/sec) for at least Sustained.
+	RuleRateAbove RuleKind = "rate_above"
+	// RuleDeviceOffline fires when a device hasn't been seen for at
+	// least Offline.
+	RuleDeviceOffline RuleKind = "device_offline"
+	// RuleDailyQuota fires when a device's cumulative bytes since
+	// midnight exceed Threshold.
+	RuleDailyQuota RuleKind = "daily_quota"
+)
+
+// AlertRule defines one condition to evaluate on every scrape. MACAddress
+// empty means "any device currently reporting" for rate_above and
+// daily_quota; device_offline always targets one specific MACAddress.
+type AlertRule struct {
+	Name       string    `json:"name"`
+	Kind       RuleKind  `json:"kind"`
+	MACAddress string    `json:"mac_address"`
+	Direction  Direction `json:"direction"`
+	// Threshold is bytes/sec for rate_above, bytes for daily_quota.
+	Threshold float64 `json:"threshold"`
+	// Hysteresis is the fraction of Threshold a rate_above condition
+	// must drop below before the alert is considered resolved, so
+	// noise right at the threshold doesn't flap the alert. Defaults to
+	// 0.9 if zero.
+	Hysteresis float64 `json:"hysteresis"`
+	// Sustained is how long rate_above's condition must hold
+	// continuously before the alert fires.
+	Sustained time.Duration `json:"sustained"`
+	// Offline is how long device_offline must see no samples before it
+	// fires.
+	Offline time.Duration `json:"offline"`
+	// Cooldown is the minimum time between repeat notifications for an
+	// alert that's still firing, so a persistent condition doesn't spam.
+	Cooldown time.Duration `json:"cooldown"`
+	// Notifiers names entries from AlertsConfig.Notifiers to dispatch to
+	// when this rule fires.
+	Notifiers []string `json:"notifiers"`
+}
+
+// Alert is one firing or resolved instance of an AlertRule against a
+// device.
+type Alert struct {
+	RuleName   string    `json:"rule_name"`
+	MACAddress string    `json:"mac_address"`
+	Message    string    `json:"message"`
+	FiredAt    time.Time `json:"fired_at"`
+	ResolvedAt time.Time `json:"resolved_at,omitempty"`
+}
+
+// Active reports whether the alert is still firing.
+func (a Alert) Active() bool {
+	return a.ResolvedAt.IsZero()
+}
+
+type ruleDeviceKey struct {
+	rule string
+	mac  string
+}
+
+type ruleState struct {
+	conditionSince time.Time // zero if the condition isn't currently true
+	lastNotified   time.Time
+	alert          *Alert // non-nil while firing
+}
+
+type dailyUsage struct {
+	day      time.Time
+	baseline int64
+}
+
+const maxAlertHistory = 200
+
+// AlertEngine evaluates AlertRules against each scrape's traffic rates and
+// dispatches Notifiers when rules fire, respecting each rule's cooldown.
+type AlertEngine struct {
+	mu        sync.Mutex
+	rules     []AlertRule
+	notifiers map[string]Notifier
+
+	states   map[ruleDeviceKey]*ruleState
+	lastSeen map[string]time.Time
+	daily    map[ruleDeviceKey]*dailyUsage
+	history  []Alert // resolved alerts, oldest first
+}
+
+// NewAlertEngine returns an engine evaluating rules, dispatching through
+// notifiers (keyed by the names an AlertRule.Notifiers references).
+func NewAlertEngine(rules []AlertRule, notifiers map[string]Notifier) *AlertEngine {
+	return &AlertEngine{
+		rules:     rules,
+		notifiers: notifiers,
+		states:    make(map[ruleDeviceKey]*ruleState),
+		lastSeen:  make(map[string]time.Time),
+		daily:     make(map[ruleDeviceKey]*dailyUsage),
+	}
+}
+
+// Evaluate runs every rule against this scrape's rates, firing or
+// resolving alerts and dispatching notifications as needed. now should be
+// the time the scrape was taken. lastSeen is updated solely from rates
+// (i.e. devices that actually produced a sample this scrape), since a
+// static device list would never reflect a device going offline.
+func (e *AlertEngine) Evaluate(ctx context.Context, now time.Time, rates []Rate) {
+	e.mu.Lock()
+	for _, r := range rates {
+		e.lastSeen[r.MACAddress] = now
+	}
+	rules := append([]AlertRule(nil), e.rules...)
+	e.mu.Unlock()
+
+	for _, rule := range rules {
+		switch rule.Kind {
+		case RuleRateAbove:
+			e.evaluateRateAbove(ctx, rule, now, rates)
+		case RuleDeviceOffline:
+			e.evaluateDeviceOffline(ctx, rule, now)
+		case RuleDailyQuota:
+			e.evaluateDailyQuota(ctx, rule, now, rates)
+		}
+	}
+}
+
+func (e *AlertEngine) evaluateRateAbove(ctx context.Context, rule AlertRule, now time.Time, rates []Rate) {
+	hysteresis := rule.Hysteresis
+	if hysteresis == 0 {
+		hysteresis = 0.9
+	}
+	for _, rate := range rates {
+		if rate.Direction != rule.Direction {
+			continue
+		}
+		if rule.MACAddress != "" && rate.MACAddress != rule.MACAddress {
+			continue
+		}
+		key := ruleDeviceKey{rule: rule.Name, mac: rate.MACAddress}
+
+		e.mu.Lock()
+		state := e.stateFor(key)
+		firing := state.alert != nil
+		threshold := rule.Threshold
+		if firing {
+			threshold *= hysteresis
+		}
+		if rate.BytesPerSec <= threshold {
+			state.conditionSince = time.Time{}
+			if firing {
+				e.resolve(state, now)
+			}
+			e.mu.Unlock()
+			continue
+		}
+		if state.conditionSince.IsZero() {
+			state.conditionSince = now
+		}
+		sustainedFor := now.Sub(state.conditionSince)
+		e.mu.Unlock()
+
+		if !firing && sustainedFor >= rule.Sustained {
+			e.fire(ctx, rule, key, now, fmt.Sprintf(
+				"%s rate %.0f B/s exceeded %.0f B/s for at least %v",
+				directionLabel(rule.Direction), rate.BytesPerSec, rule.Threshold, rule.Sustained))
+		} else if firing {
+			e.renotifyIfDue(ctx, rule, key, now)
+		}
+	}
+}
+
+func (e *AlertEngine) evaluateDeviceOffline(ctx context.Context, rule AlertRule, now time.Time) {
+	if rule.MACAddress == "" {
+		return
+	}
+	key := ruleDeviceKey{rule: rule.Name, mac: rule.MACAddress}
+
+	e.mu.Lock()
+	state := e.stateFor(key)
+	firing := state.alert != nil
+	lastSeen, seen := e.lastSeen[rule.MACAddress]
+	offlineFor := rule.Offline
+	if seen {
+		offlineFor = now.Sub(lastSeen)
+	}
+	conditionTrue := !seen || offlineFor >= rule.Offline
+	if !conditionTrue {
+		if firing {
+			e.resolve(state, now)
+		}
+		e.mu.Unlock()
+		return
+	}
+	e.mu.Unlock()
+
+	if !firing {
+		e.fire(ctx, rule, key, now, fmt.Sprintf("device not seen for at least %v", offlineFor.Round(time.Second)))
+	} else {
+		e.renotifyIfDue(ctx, rule, key, now)
+	}
+}
+
+func (e *AlertEngine) evaluateDailyQuota(ctx context.Context, rule AlertRule, now time.Time, rates []Rate) {
+	dayStart := startOfDay(now)
+	for _, rate := range rates {
+		if rate.Direction != rule.Direction {
+			continue
+		}
+		if rule.MACAddress != "" && rate.MACAddress != rule.MACAddress {
+			continue
+		}
+		key := ruleDeviceKey{rule: rule.Name, mac: rate.MACAddress}
+
+		e.mu.Lock()
+		usage, ok := e.daily[key]
+		if !ok || usage.day.Before(dayStart) {
+			usage = &dailyUsage{day: dayStart, baseline: rate.CumulativeBytes}
+			e.daily[key] = usage
+		}
+		usedToday := rate.CumulativeBytes - usage.baseline
+		state := e.stateFor(key)
+		firing := state.alert != nil
+		conditionTrue := float64(usedToday) > rule.Threshold
+		if !conditionTrue {
+			if firing {
+				e.resolve(state, now)
+			}
+			e.mu.Unlock()
+			continue
+		}
+		e.mu.Unlock()
+
+		if !firing {
+			e.fire(ctx, rule, key, now, fmt.Sprintf(
+				"%s cumulative %d bytes today exceeded quota of %.0f", directionLabel(rule.Direction), usedToday, rule.Threshold))
+		} else {
+			e.renotifyIfDue(ctx, rule, key, now)
+		}
+	}
+}
+
+// stateFor returns the ruleState for key, creating it if necessary. Callers
+// must hold e.mu.
+func (e *AlertEngine) stateFor(key ruleDeviceKey) *ruleState {
+	state, ok := e.states[key]
+	if !ok {
+		state = &ruleState{}
+		e.states[key] = state
+	}
+	return state
+}
+
+// resolve marks the firing alert for key resolved and moves it to history.
+// Callers must hold e.mu.
+func (e *AlertEngine) resolve(state *ruleState, now time.Time) {
+	if state.alert == nil {
+		return
+	}
+	resolved := *state.alert
+	resolved.ResolvedAt = now
+	e.history = append(e.history, resolved)
+	if len(e.history) > maxAlertHistory {
+		e.history = e.history[len(e.history)-maxAlertHistory:]
+	}
+	state.alert = nil
+	state.conditionSince = time.Time{}
+}
+
+// fire starts a new alert for key and dispatches it to the rule's
+// notifiers.
+func (e *AlertEngine) fire(ctx context.Context, rule AlertRule, key ruleDeviceKey, now time.Time, message string) {
+	alert := Alert{
+		RuleName:   rule.Name,
+		MACAddress: key.mac,
+		Message:    fmt.Sprintf("%s: %s", rule.Name, message),
+		FiredAt:    now,
+	}
+	e.mu.Lock()
+	state := e.stateFor(key)
+	state.alert = &alert
+	state.lastNotified = now
+	e.mu.Unlock()
+	e.notify(ctx, rule, alert)
+}
+
+// renotifyIfDue re-dispatches an already-firing alert if its rule's
+// cooldown has elapsed since the last notification.
+func (e *AlertEngine) renotifyIfDue(ctx context.Context, rule AlertRule, key ruleDeviceKey, now time.Time) {
+	e.mu.Lock()
+	state := e.stateFor(key)
+	if state.alert == nil || now.Sub(state.lastNotified) < rule.Cooldown {
+		e.mu.Unlock()
+		return
+	}
+	state.lastNotified = now
+	alert := *state.alert
+	e.mu.Unlock()
+	e.notify(ctx, rule, alert)
+}
+
+func (e *AlertEngine) notify(ctx context.Context, rule AlertRule, alert Alert) {
+	for _, name := range rule.Notifiers {
+		notifier, ok := e.notifiers[name]
+		if !ok {
+			log.Printf("alert rule %q references unknown notifier %q", rule.Name, name)
+			continue
+		}
+		if err := notifier.Notify(ctx, alert); err != nil {
+			log.Printf("notifier %q failed: %v", name, err)
+		}
+	}
+}
+
+// Alerts returns currently-active alerts followed by recently resolved
+// ones, most recently fired/resolved first within each group.
+func (e *AlertEngine) Alerts() []Alert {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var active []Alert
+	for _, state := range e.states {
+		if state.alert != nil {
+			active = append(active, *state.alert)
+		}
+	}
+	recent := make([]Alert, len(e.history))
+	for i, alert := range e.history {
+		recent[len(e.history)-1-i] = alert
+	}
+	return append(active, recent...)
+}
+
+func directionLabel(d Direction) string {
+	if d == DirectionReceive {
+		return "receive"
+	}
+	return "transmit"
+}
+
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type recordingNotifier struct {
+	alerts []Alert
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, alert Alert) error {
+	n.alerts = append(n.alerts, alert)
+	return nil
+}
+
+func TestAlertEngineRateAboveFiresAfterSustainedAndRespectsCooldown(t *testing.T) {
+	notifier := &recordingNotifier{}
+	engine := NewAlertEngine([]AlertRule{{
+		Name:      "high-transmit",
+		Kind:      RuleRateAbove,
+		Direction: DirectionTransmit,
+		Threshold: 1000,
+		Sustained: 20 * time.Second,
+		Cooldown:  time.Minute,
+		Notifiers: []string{"test"},
+	}}, map[string]Notifier{"test": notifier})
+
+	now := time.Unix(1000, 0)
+	rate := Rate{MACAddress: "aa:bb", Direction: DirectionTransmit, BytesPerSec: 2000}
+
+	engine.Evaluate(context.Background(), now, []Rate{rate})
+	if len(notifier.alerts) != 0 {
+		t.Fatalf("expected no alert before the sustained window elapses, got %d", len(notifier.alerts))
+	}
+
+	now = now.Add(25 * time.Second)
+	engine.Evaluate(context.Background(), now, []Rate{rate})
+	if len(notifier.alerts) != 1 {
+		t.Fatalf("expected one alert once the condition has been sustained, got %d", len(notifier.alerts))
+	}
+
+	// Still firing, but within cooldown: no repeat notification.
+	now = now.Add(10 * time.Second)
+	engine.Evaluate(context.Background(), now, []Rate{rate})
+	if len(notifier.alerts) != 1 {
+		t.Fatalf("expected cooldown to suppress a repeat notification, got %d", len(notifier.alerts))
+	}
+}
+
+func TestAlertEngineRateAboveResolvesBelowHysteresisThreshold(t *testing.T) {
+	notifier := &recordingNotifier{}
+	engine := NewAlertEngine([]AlertRule{{
+		Name:       "high-transmit",
+		Kind:       RuleRateAbove,
+		Direction:  DirectionTransmit,
+		Threshold:  1000,
+		Hysteresis: 0.5,
+		Sustained:  0,
+		Cooldown:   time.Minute,
+		Notifiers:  []string{"test"},
+	}}, map[string]Notifier{"test": notifier})
+
+	now := time.Unix(1000, 0)
+	engine.Evaluate(context.Background(), now, []Rate{{MACAddress: "aa:bb", Direction: DirectionTransmit, BytesPerSec: 2000}})
+	if active := engine.Alerts(); len(active) != 1 || !active[0].Active() {
+		t.Fatalf("expected the alert to be active after firing, got %+v", active)
+	}
+
+	// Dropping to just under the raw threshold shouldn't resolve the
+	// alert yet: hysteresis requires it fall under threshold*0.5=500.
+	now = now.Add(time.Second)
+	engine.Evaluate(context.Background(), now, []Rate{{MACAddress: "aa:bb", Direction: DirectionTransmit, BytesPerSec: 900}})
+	if active := engine.Alerts(); len(active) != 1 || !active[0].Active() {
+		t.Fatalf("expected the alert to remain active within the hysteresis band, got %+v", active)
+	}
+
+	now = now.Add(time.Second)
+	engine.Evaluate(context.Background(), now, []Rate{{MACAddress: "aa:bb", Direction: DirectionTransmit, BytesPerSec: 100}})
+	alerts := engine.Alerts()
+	if len(alerts) != 1 || alerts[0].Active() {
+		t.Fatalf("expected the alert to resolve once the rate drops below the hysteresis threshold, got %+v", alerts)
+	}
+}
+
+func TestAlertEngineDeviceOfflineFiresWhenUnseen(t *testing.T) {
+	notifier := &recordingNotifier{}
+	engine := NewAlertEngine([]AlertRule{{
+		Name:       "device-gone",
+		Kind:       RuleDeviceOffline,
+		MACAddress: "aa:bb",
+		Offline:    time.Minute,
+		Cooldown:   time.Minute,
+		Notifiers:  []string{"test"},
+	}}, map[string]Notifier{"test": notifier})
+
+	now := time.Unix(1000, 0)
+	engine.Evaluate(context.Background(), now, []Rate{{MACAddress: "aa:bb", Direction: DirectionTransmit}})
+	if len(notifier.alerts) != 0 {
+		t.Fatalf("expected no alert while the device is still seen")
+	}
+
+	now = now.Add(2 * time.Minute)
+	engine.Evaluate(context.Background(), now, nil)
+	if len(notifier.alerts) != 1 {
+		t.Fatalf("expected an alert once the device has been unseen past the offline window, got %d", len(notifier.alerts))
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// defaultFetchTimeout bounds a single gateway page fetch when
+// ATTGatewayConfig.Timeout isn't set, so a hung router page can't freeze
+// the scrape loop indefinitely.
+const defaultFetchTimeout = 10 * time.Second
+
+// ATTGatewayConfig holds the connection details for the AT&T-style gateway
+// HTML backend.
+type ATTGatewayConfig struct {
+	// BaseURL is the gateway's base address, e.g. "http://192.168.1.254".
+	BaseURL string
+	// Username/Password are sent as HTTP basic auth if Username is set.
+	Username string
+	Password string
+	// Timeout bounds each HTTP fetch; defaults to defaultFetchTimeout.
+	Timeout time.Duration
+}
+
+// ATTGatewayBackend implements RouterBackend by scraping the HTML
+// admin pages of an AT&T-style residential gateway.
+type ATTGatewayBackend struct {
+	cfg    ATTGatewayConfig
+	client *http.Client
+}
+
+// NewATTGatewayBackend returns a RouterBackend that scrapes the gateway at
+// cfg.BaseURL. An empty BaseURL defaults to the stock AT&T gateway address.
+func NewATTGatewayBackend(cfg ATTGatewayConfig) *ATTGatewayBackend {
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = "http://192.168.1.254"
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = defaultFetchTimeout
+	}
+	return &ATTGatewayBackend{cfg: cfg, client: &http.Client{Timeout: cfg.Timeout}}
+}
+
+func (b *ATTGatewayBackend) fetch(ctx context.Context, path string) (*html.Node, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.cfg.BaseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if b.cfg.Username != "" {
+		req.SetBasicAuth(b.cfg.Username, b.cfg.Password)
+	}
+	return fetchRequest(b.client, req)
+}
+
+func (b *ATTGatewayBackend) ListDevices(ctx context.Context) ([]DeviceInfo, error) {
+	doc, err := b.fetch(ctx, "/cgi-bin/devices.ha")
+	if err != nil {
+		return nil, err
+	}
+	table := findDescendant(doc, func(node *html.Node) bool {
+		return node.DataAtom == atom.Table && getAttribute(node, "summary") == "This table displays info for each LAN-side device"
+	})
+	if table == nil {
+		return nil, nil
+	}
+	var devices []DeviceInfo
+	for tr := findDescendant(table, func(node *html.Node) bool {
+		return node.DataAtom == atom.Tr
+	}); tr != nil; tr = findFollowupSibling(tr) {
+		th := findDescendant(tr, func(node *html.Node) bool {
+			return node.DataAtom == atom.Th && getInnerText(node) == "MAC Address"
+		})
+		if th == nil {
+			continue
+		}
+		td := findDescendant(tr, func(node *html.Node) bool {
+			return node.DataAtom == atom.Td
+		})
+		if td == nil {
+			continue
+		}
+		macAddress := strings.Trim(getInnerText(td), " \t\n")
+		tr = findFollowupSibling(tr)
+		if tr == nil {
+			continue
+		}
+		td = findDescendant(tr, func(node *html.Node) bool {
+			return node.DataAtom == atom.Td
+		})
+		if td == nil {
+			continue
+		}
+		devices = append(devices, DeviceInfo{
+			MACAddress: macAddress,
+			Name:       strings.ReplaceAll(strings.Trim(getInnerText(td), " \t\n"), "\n", ""),
+		})
+	}
+	return devices, nil
+}
+
+func (b *ATTGatewayBackend) SampleTraffic(ctx context.Context) ([]TrafficSample, error) {
+	doc, err := b.fetch(ctx, "/cgi-bin/lanstatistics.ha")
+	if err != nil {
+		return nil, err
+	}
+	table := findDescendant(doc, func(node *html.Node) bool {
+		return node.DataAtom == atom.Table && getAttribute(node, "summary") == "Wi-Fi Client Connection Statistics Table"
+	})
+	if table == nil {
+		return nil, fmt.Errorf("Table not found")
+	}
+	tr := findDescendant(table, func(node *html.Node) bool {
+		return node.DataAtom == atom.Tr
+	})
+	if tr == nil {
+		return nil, fmt.Errorf("Tr not found")
+	}
+	var samples []TrafficSample
+	for tr = findFollowupSibling(tr); tr != nil; tr = findFollowupSibling(tr) {
+		columnIndex := 0
+		sample := TrafficSample{}
+		haveMAC := false
+		for td := findDescendant(tr, func(node *html.Node) bool {
+			return node.DataAtom == atom.Td
+		}); td != nil; td = findFollowupSibling(td) {
+			if columnIndex == 0 {
+				sample.MACAddress = strings.Trim(getInnerText(td), " \t\n")
+				haveMAC = true
+			}
+			if haveMAC && columnIndex == 7 {
+				if bytes, err := strconv.ParseInt(strings.Trim(getInnerText(td), " \t\n"), 10, 32); err == nil {
+					sample.TransmitBytes = bytes
+				}
+			}
+			if haveMAC && columnIndex == 8 {
+				if bytes, err := strconv.ParseInt(strings.Trim(getInnerText(td), " \t\n"), 10, 32); err == nil {
+					sample.ReceiveBytes = bytes
+				}
+			}
+			columnIndex++
+		}
+		if haveMAC {
+			samples = append(samples, sample)
+		}
+	}
+	return samples, nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestATTGatewayBackendAbortsOnTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.Write([]byte("<html></html>"))
+	}))
+	defer srv.Close()
+
+	backend := NewATTGatewayBackend(ATTGatewayConfig{BaseURL: srv.URL, Timeout: 20 * time.Millisecond})
+
+	start := time.Now()
+	_, err := backend.ListDevices(context.Background())
+	elapsed := time.Since(start)
+
+	if err == nil {
+		t.Fatalf("expected an error from a slow response exceeding the timeout")
+	}
+	if elapsed > 150*time.Millisecond {
+		t.Fatalf("ListDevices took %v, expected it to abort near the 20ms timeout", elapsed)
+	}
+}
+
+func TestATTGatewayBackendContextCancellation(t *testing.T) {
+	block := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-block
+	}))
+	defer srv.Close()
+	defer close(block)
+
+	backend := NewATTGatewayBackend(ATTGatewayConfig{BaseURL: srv.URL, Timeout: time.Minute})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := backend.ListDevices(ctx)
+		errCh <- err
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-errCh:
+		if err == nil {
+			t.Fatalf("expected an error after cancelling the context")
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("ListDevices did not return after context cancellation")
+	}
+}
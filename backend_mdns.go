@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/hashicorp/mdns"
+)
+
+// MDNSConfig holds the lookup parameters for the mDNS/DNS-SD naming
+// overlay.
+type MDNSConfig struct {
+	// Enabled layers an MDNSNamingBackend over whichever backend
+	// cfg.Backend selects.
+	Enabled bool
+	// Service is the DNS-SD service type to browse, e.g. "_device-info._tcp".
+	Service string
+	// Timeout bounds each browse; defaults to 3s if zero.
+	Timeout time.Duration
+}
+
+// MDNSNamingBackend wraps another RouterBackend and replaces each device's
+// Name with its mDNS/DNS-SD hostname, for friendlier names than a router's
+// admin pages usually report. mDNS has no notion of a device's MAC address
+// or traffic counters, only its IP and hostname, so it can't stand on its
+// own as a RouterBackend; it only enriches names on devices whose Name is
+// already the device's IP, which is what the SNMP backend reports.
+// SampleTraffic is delegated to inner unchanged.
+type MDNSNamingBackend struct {
+	inner RouterBackend
+	cfg   MDNSConfig
+}
+
+// NewMDNSNamingBackend returns a RouterBackend that enriches inner's
+// ListDevices results with hostnames browsed from cfg.Service over mDNS.
+// An empty Service defaults to "_device-info._tcp".
+func NewMDNSNamingBackend(inner RouterBackend, cfg MDNSConfig) *MDNSNamingBackend {
+	if cfg.Service == "" {
+		cfg.Service = "_device-info._tcp"
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 3 * time.Second
+	}
+	return &MDNSNamingBackend{inner: inner, cfg: cfg}
+}
+
+func (b *MDNSNamingBackend) ListDevices(ctx context.Context) ([]DeviceInfo, error) {
+	devices, err := b.inner.ListDevices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names, err := b.namesByIP(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i, device := range devices {
+		if name, ok := names[device.Name]; ok {
+			devices[i].Name = name
+		}
+	}
+	return devices, nil
+}
+
+func (b *MDNSNamingBackend) SampleTraffic(ctx context.Context) ([]TrafficSample, error) {
+	return b.inner.SampleTraffic(ctx)
+}
+
+// namesByIP browses cfg.Service over mDNS and returns the hostname each
+// responder advertised, keyed by its IPv4 address.
+func (b *MDNSNamingBackend) namesByIP(ctx context.Context) (map[string]string, error) {
+	entries := make(chan *mdns.ServiceEntry, 16)
+	names := map[string]string{}
+	done := make(chan struct{})
+	go func() {
+		for entry := range entries {
+			names[entry.AddrV4.String()] = entry.Name
+		}
+		close(done)
+	}()
+
+	err := mdns.Query(&mdns.QueryParam{
+		Service: b.cfg.Service,
+		Timeout: b.cfg.Timeout,
+		Entries: entries,
+	})
+	close(entries)
+	<-done
+	if err != nil {
+		return nil, err
+	}
+	return names, nil
+}
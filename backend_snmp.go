@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gosnmp/gosnmp"
+)
+
+// SNMPConfig holds the connection details for the SNMP backend. Target is
+// the router's IP or hostname; Community is used for v1/v2c, while the V3*
+// fields are used when Version is "v3".
+type SNMPConfig struct {
+	Target    string
+	Port      uint16
+	Version   string // "v1", "v2c" (default), or "v3"
+	Community string
+
+	V3Username     string
+	V3AuthProtocol string
+	V3AuthPassword string
+	V3PrivProtocol string
+	V3PrivPassword string
+}
+
+const (
+	oidIfInOctets              = "1.3.6.1.2.1.2.2.1.10"
+	oidIfOutOctets             = "1.3.6.1.2.1.2.2.1.16"
+	oidIPNetToMediaPhysAddress = "1.3.6.1.2.1.4.22.1.2"
+)
+
+// SNMPBackend implements RouterBackend by walking standard IF-MIB and
+// IP-MIB tables on the router.
+type SNMPBackend struct {
+	cfg SNMPConfig
+}
+
+// NewSNMPBackend validates cfg and returns a RouterBackend that talks SNMP
+// to cfg.Target.
+func NewSNMPBackend(cfg SNMPConfig) (*SNMPBackend, error) {
+	if cfg.Target == "" {
+		return nil, fmt.Errorf("snmp backend: Target is required")
+	}
+	if cfg.Port == 0 {
+		cfg.Port = 161
+	}
+	return &SNMPBackend{cfg: cfg}, nil
+}
+
+func (b *SNMPBackend) newClient(ctx context.Context) (*gosnmp.GoSNMP, error) {
+	client := &gosnmp.GoSNMP{
+		Target:  b.cfg.Target,
+		Port:    b.cfg.Port,
+		Timeout: 5 * time.Second,
+		Retries: 1,
+	}
+	switch b.cfg.Version {
+	case "v3":
+		client.Version = gosnmp.Version3
+		client.SecurityModel = gosnmp.UserSecurityModel
+		client.MsgFlags = gosnmp.AuthPriv
+		client.SecurityParameters = &gosnmp.UsmSecurityParameters{
+			UserName:                 b.cfg.V3Username,
+			AuthenticationProtocol:   gosnmp.SHA,
+			AuthenticationPassphrase: b.cfg.V3AuthPassword,
+			PrivacyProtocol:          gosnmp.AES,
+			PrivacyPassphrase:        b.cfg.V3PrivPassword,
+		}
+	case "v1":
+		client.Version = gosnmp.Version1
+		client.Community = b.cfg.Community
+	default:
+		client.Version = gosnmp.Version2c
+		client.Community = b.cfg.Community
+	}
+	if err := client.Connect(); err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// ListDevices maps MAC addresses to IPs via ipNetToMediaPhysAddress; the
+// router's ARP/neighbor table is the closest SNMP has to a device name, so
+// the IP is used as the Name.
+func (b *SNMPBackend) ListDevices(ctx context.Context) ([]DeviceInfo, error) {
+	client, err := b.newClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Conn.Close()
+
+	var devices []DeviceInfo
+	err = client.BulkWalk(oidIPNetToMediaPhysAddress, func(pdu gosnmp.SnmpPDU) error {
+		mac, ok := pdu.Value.([]byte)
+		if !ok || len(mac) != 6 {
+			return nil
+		}
+		_, ip := splitPhysAddressOID(pdu.Name, oidIPNetToMediaPhysAddress)
+		devices = append(devices, DeviceInfo{
+			MACAddress: formatMACAddress(mac),
+			Name:       ip,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return devices, nil
+}
+
+// SampleTraffic walks ifInOctets/ifOutOctets, which IF-MIB keys by
+// interface index rather than MAC address. Since a consumer router has no
+// standard per-client counters, each interface's totals are attributed to
+// every neighbor MAC the ARP table (ipNetToMediaPhysAddress) reports on
+// that interface, matching the MAC keys ListDevices produces. Interfaces
+// with no known neighbor yet (nothing has ARPed on them) produce no
+// sample.
+func (b *SNMPBackend) SampleTraffic(ctx context.Context) ([]TrafficSample, error) {
+	client, err := b.newClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Conn.Close()
+
+	macsByIfIndex, err := b.neighborMACsByIfIndex(client)
+	if err != nil {
+		return nil, err
+	}
+
+	counters := map[string]*ifCounters{}
+	counterFor := func(ifIndex string) *ifCounters {
+		c, ok := counters[ifIndex]
+		if !ok {
+			c = &ifCounters{}
+			counters[ifIndex] = c
+		}
+		return c
+	}
+	if err := walkCounter(client, oidIfInOctets, func(ifIndex string, v int64) { counterFor(ifIndex).receive = v }); err != nil {
+		return nil, err
+	}
+	if err := walkCounter(client, oidIfOutOctets, func(ifIndex string, v int64) { counterFor(ifIndex).transmit = v }); err != nil {
+		return nil, err
+	}
+
+	return joinCountersToNeighborMACs(counters, macsByIfIndex), nil
+}
+
+type ifCounters struct {
+	transmit, receive int64
+}
+
+// joinCountersToNeighborMACs attributes each interface's counters to every
+// neighbor MAC known on it, producing one TrafficSample per (interface,
+// neighbor) pair.
+func joinCountersToNeighborMACs(counters map[string]*ifCounters, macsByIfIndex map[string][]string) []TrafficSample {
+	var samples []TrafficSample
+	for ifIndex, c := range counters {
+		for _, mac := range macsByIfIndex[ifIndex] {
+			samples = append(samples, TrafficSample{
+				MACAddress:    mac,
+				TransmitBytes: c.transmit,
+				ReceiveBytes:  c.receive,
+			})
+		}
+	}
+	return samples
+}
+
+// neighborMACsByIfIndex walks ipNetToMediaPhysAddress and groups the MAC
+// addresses it finds by the interface index they were learned on.
+func (b *SNMPBackend) neighborMACsByIfIndex(client *gosnmp.GoSNMP) (map[string][]string, error) {
+	macsByIfIndex := map[string][]string{}
+	err := client.BulkWalk(oidIPNetToMediaPhysAddress, func(pdu gosnmp.SnmpPDU) error {
+		mac, ok := pdu.Value.([]byte)
+		if !ok || len(mac) != 6 {
+			return nil
+		}
+		ifIndex, _ := splitPhysAddressOID(pdu.Name, oidIPNetToMediaPhysAddress)
+		macsByIfIndex[ifIndex] = append(macsByIfIndex[ifIndex], formatMACAddress(mac))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return macsByIfIndex, nil
+}
+
+func walkCounter(client *gosnmp.GoSNMP, oid string, assign func(ifIndex string, value int64)) error {
+	return client.BulkWalk(oid, func(pdu gosnmp.SnmpPDU) error {
+		idx := strings.TrimPrefix(pdu.Name, "."+oid+".")
+		assign(idx, gosnmp.ToBigInt(pdu.Value).Int64())
+		return nil
+	})
+}
+
+func formatMACAddress(mac []byte) string {
+	parts := make([]string, len(mac))
+	for i, b := range mac {
+		parts[i] = fmt.Sprintf("%02x", b)
+	}
+	return strings.Join(parts, ":")
+}
+
+// splitPhysAddressOID splits the "ifIndex.a.b.c.d" instance suffix of an
+// ipNetToMediaPhysAddress OID into its interface index and dotted-quad IP.
+func splitPhysAddressOID(oid, base string) (ifIndex, ip string) {
+	suffix := strings.TrimPrefix(oid, "."+base+".")
+	parts := strings.SplitN(suffix, ".", 2)
+	if len(parts) != 2 {
+		return "", ""
+	}
+	return parts[0], parts[1]
+}
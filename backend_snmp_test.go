@@ -0,0 +1,55 @@
+package main
+
+import "testing"
+
+func TestSplitPhysAddressOID(t *testing.T) {
+	cases := []struct {
+		oid         string
+		wantIfIndex string
+		wantIP      string
+	}{
+		{".1.3.6.1.2.1.4.22.1.2.4.192.168.1.42", "4", "192.168.1.42"},
+		{".1.3.6.1.2.1.4.22.1.2.12.10.0.0.1", "12", "10.0.0.1"},
+		{".1.3.6.1.2.1.4.22.1.2.bogus", "", ""},
+	}
+	for _, c := range cases {
+		ifIndex, ip := splitPhysAddressOID(c.oid, oidIPNetToMediaPhysAddress)
+		if ifIndex != c.wantIfIndex || ip != c.wantIP {
+			t.Errorf("splitPhysAddressOID(%q) = (%q, %q), want (%q, %q)", c.oid, ifIndex, ip, c.wantIfIndex, c.wantIP)
+		}
+	}
+}
+
+// TestJoinCountersToNeighborMACsAttributesInterfaceToItsNeighbors covers
+// the join SampleTraffic relies on: a walked interface's counters must land
+// on the real MAC addresses ListDevices reports (via the ARP table), not
+// the interface's own description string, and an interface with no known
+// neighbor yet produces no sample.
+func TestJoinCountersToNeighborMACsAttributesInterfaceToItsNeighbors(t *testing.T) {
+	counters := map[string]*ifCounters{
+		"4": {transmit: 2000, receive: 1000},
+		"7": {transmit: 500, receive: 250},
+	}
+	macsByIfIndex := map[string][]string{
+		"4": {"aa:bb:cc:dd:ee:ff", "11:22:33:44:55:66"},
+	}
+
+	samples := joinCountersToNeighborMACs(counters, macsByIfIndex)
+
+	if len(samples) != 2 {
+		t.Fatalf("expected one sample per neighbor MAC on if 4 and none for if 7, got %d: %+v", len(samples), samples)
+	}
+	byMAC := map[string]TrafficSample{}
+	for _, s := range samples {
+		byMAC[s.MACAddress] = s
+	}
+	for _, mac := range []string{"aa:bb:cc:dd:ee:ff", "11:22:33:44:55:66"} {
+		s, ok := byMAC[mac]
+		if !ok {
+			t.Fatalf("expected a sample for neighbor %s, got %+v", mac, samples)
+		}
+		if s.TransmitBytes != 2000 || s.ReceiveBytes != 1000 {
+			t.Fatalf("expected if 4's counters to carry over unchanged for %s, got %+v", mac, s)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+)
+
+// Config is the top-level on-disk configuration, loaded as JSON via the
+// -config flag. Only the section matching Backend needs to be filled in.
+type Config struct {
+	Backend BackendKind `json:"backend"`
+
+	ATTGateway ATTGatewayConfig `json:"att_gateway"`
+	SNMP       SNMPConfig       `json:"snmp"`
+	UPnP       UPnPConfig       `json:"upnp"`
+	MDNS       MDNSConfig       `json:"mdns"`
+
+	// DBPath is where the SQLite history store lives. Defaults to
+	// "network_monitor.db" in the working directory.
+	DBPath string `json:"db_path"`
+	// LegacySnapshotPath, if set, points at a JSON dump of the old
+	// in-memory-only ChartData format to import into the store on
+	// startup. It is only ever read once; there's no harm leaving it
+	// configured after the import has happened, since already-imported
+	// samples are just overwritten with identical values.
+	LegacySnapshotPath string `json:"legacy_snapshot_path"`
+
+	Alerts AlertsConfig `json:"alerts"`
+}
+
+// AlertsConfig configures the alerting subsystem: the rules to evaluate
+// and the named notifiers they can dispatch to.
+type AlertsConfig struct {
+	Rules     []AlertRule      `json:"rules"`
+	Notifiers []NotifierConfig `json:"notifiers"`
+}
+
+// NotifierConfig names and configures one Notifier; Kind selects which of
+// Webhook/SMTP is read ("webhook", "smtp", or "log" for NoopNotifier,
+// which needs no further config).
+type NotifierConfig struct {
+	Name    string        `json:"name"`
+	Kind    string        `json:"kind"`
+	Webhook WebhookConfig `json:"webhook"`
+	SMTP    SMTPConfig    `json:"smtp"`
+}
+
+// buildNotifiers constructs the named Notifier set described by cfg.
+func buildNotifiers(cfg AlertsConfig) map[string]Notifier {
+	notifiers := make(map[string]Notifier, len(cfg.Notifiers))
+	for _, nc := range cfg.Notifiers {
+		switch nc.Kind {
+		case "webhook":
+			notifiers[nc.Name] = NewWebhookNotifier(nc.Webhook)
+		case "smtp":
+			notifiers[nc.Name] = NewSMTPNotifier(nc.SMTP)
+		default:
+			notifiers[nc.Name] = NoopNotifier{}
+		}
+	}
+	return notifiers
+}
+
+// loadConfig reads and parses the config file at path. A missing path
+// (empty string) returns the zero Config, which selects the AT&T gateway
+// backend with its default address.
+func loadConfig(path string) (*Config, error) {
+	cfg := &Config{}
+	if path == "" {
+		return cfg, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if err := json.NewDecoder(f).Decode(cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// configFromFlags parses command-line flags, applying overrides for the
+// most commonly tuned settings on top of whatever -config points at.
+func configFromFlags() (*Config, error) {
+	configPath := flag.String("config", "", "path to a JSON config file selecting and configuring the router backend")
+	backend := flag.String("backend", "", "router backend to use: att-gateway (default), snmp, or upnp; overrides the config file (mdns is naming-only and cannot be selected on its own)")
+	mdnsNaming := flag.Bool("mdns-naming", false, "layer an mDNS/DNS-SD naming overlay over the selected backend to enrich device names; overrides the config file")
+	attBaseURL := flag.String("att-base-url", "", "base URL of the AT&T-style gateway; overrides the config file")
+	snmpTarget := flag.String("snmp-target", "", "SNMP target host; overrides the config file")
+	snmpCommunity := flag.String("snmp-community", "", "SNMP v1/v2c community string; overrides the config file")
+	dbPath := flag.String("db", "", "path to the SQLite history database; overrides the config file, defaults to network_monitor.db")
+	legacySnapshot := flag.String("legacy-snapshot", "", "path to a legacy in-memory ChartData JSON dump to import into the history store on startup")
+	flag.Parse()
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		return nil, err
+	}
+	if *backend != "" {
+		cfg.Backend = BackendKind(*backend)
+	}
+	if *mdnsNaming {
+		cfg.MDNS.Enabled = true
+	}
+	if *attBaseURL != "" {
+		cfg.ATTGateway.BaseURL = *attBaseURL
+	}
+	if *snmpTarget != "" {
+		cfg.SNMP.Target = *snmpTarget
+	}
+	if *snmpCommunity != "" {
+		cfg.SNMP.Community = *snmpCommunity
+	}
+	if *dbPath != "" {
+		cfg.DBPath = *dbPath
+	}
+	if cfg.DBPath == "" {
+		cfg.DBPath = "network_monitor.db"
+	}
+	if *legacySnapshot != "" {
+		cfg.LegacySnapshotPath = *legacySnapshot
+	}
+	return cfg, nil
+}
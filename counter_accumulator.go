@@ -0,0 +1,106 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// counter32Max is one past the largest value a 32-bit counter can hold,
+// matching the 32-bit parse width fetchLanStatistics/ATTGatewayBackend use
+// for the raw transmit/receive figures.
+const counter32Max = int64(1) << 32
+
+// wrapThreshold is how close to counter32Max the previous reading has to be
+// for a decrease to be treated as wraparound rather than a counter reset
+// (e.g. the device rebooting and restarting its counters from zero).
+const wrapThreshold = counter32Max / 4 * 3
+
+// Direction identifies which of a device's two counters a reading is for.
+type Direction int
+
+const (
+	DirectionTransmit Direction = iota
+	DirectionReceive
+)
+
+// Rate is a computed bytes/sec sample for one device/direction, derived
+// from two consecutive raw counter readings.
+type Rate struct {
+	MACAddress      string
+	Direction       Direction
+	BytesPerSec     float64
+	CumulativeBytes int64
+	At              time.Time
+}
+
+type counterKey struct {
+	mac       string
+	direction Direction
+}
+
+type counterState struct {
+	rawValue   int64
+	cumulative int64
+	at         time.Time
+}
+
+// CounterAccumulator tracks the last raw counter reading per (device,
+// direction) and turns successive cumulative readings into per-interval
+// deltas and bytes/sec rates, handling counter resets and 32-bit
+// wraparound so neither shows up as a nonsensical negative or enormous
+// spike.
+type CounterAccumulator struct {
+	mu   sync.Mutex
+	last map[counterKey]counterState
+}
+
+// NewCounterAccumulator returns an empty accumulator.
+func NewCounterAccumulator() *CounterAccumulator {
+	return &CounterAccumulator{last: make(map[counterKey]counterState)}
+}
+
+// Observe records a new raw counter reading for mac/direction at time at,
+// and returns the Rate computed against the previous reading. ok is false
+// for the first reading seen for a given device/direction, since there's
+// nothing yet to compute a delta against.
+func (a *CounterAccumulator) Observe(mac string, direction Direction, rawValue int64, at time.Time) (rate Rate, ok bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	key := counterKey{mac: mac, direction: direction}
+	prev, seen := a.last[key]
+	if !seen {
+		a.last[key] = counterState{rawValue: rawValue, cumulative: 0, at: at}
+		return Rate{}, false
+	}
+
+	diff := rawValue - prev.rawValue
+	switch {
+	case diff >= 0:
+		// Normal case: the counter only ever increases between reads.
+	case prev.rawValue >= wrapThreshold:
+		// The previous reading was near the 32-bit ceiling and the new one
+		// is lower: the counter wrapped around through zero.
+		diff = (counter32Max - prev.rawValue) + rawValue
+	default:
+		// The counter is lower than before but wasn't near the ceiling:
+		// it was reset (e.g. the device rebooted), so the new reading is
+		// itself the amount accumulated since the reset.
+		diff = rawValue
+	}
+
+	cumulative := prev.cumulative + diff
+	elapsed := at.Sub(prev.at).Seconds()
+	rate = Rate{
+		MACAddress:      mac,
+		Direction:       direction,
+		CumulativeBytes: cumulative,
+		At:              at,
+	}
+	if elapsed > 0 {
+		rate.BytesPerSec = float64(diff) / elapsed
+	}
+
+	a.last[key] = counterState{rawValue: rawValue, cumulative: cumulative, at: at}
+	return rate, true
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCounterAccumulatorFirstObservationHasNoRate(t *testing.T) {
+	a := NewCounterAccumulator()
+	t0 := time.Unix(1000, 0)
+	_, ok := a.Observe("aa:bb", DirectionTransmit, 1000, t0)
+	if ok {
+		t.Fatalf("expected first observation to report ok=false")
+	}
+}
+
+func TestCounterAccumulatorNormalDelta(t *testing.T) {
+	a := NewCounterAccumulator()
+	t0 := time.Unix(1000, 0)
+	a.Observe("aa:bb", DirectionTransmit, 1000, t0)
+
+	t1 := t0.Add(10 * time.Second)
+	rate, ok := a.Observe("aa:bb", DirectionTransmit, 6000, t1)
+	if !ok {
+		t.Fatalf("expected ok=true on second observation")
+	}
+	if rate.CumulativeBytes != 5000 {
+		t.Errorf("CumulativeBytes = %d, want 5000", rate.CumulativeBytes)
+	}
+	if rate.BytesPerSec != 500 {
+		t.Errorf("BytesPerSec = %v, want 500", rate.BytesPerSec)
+	}
+}
+
+func TestCounterAccumulatorMissedSampleGap(t *testing.T) {
+	// A longer-than-usual gap between reads (e.g. a dropped scrape)
+	// should still produce a correct average rate over the gap, not a
+	// rate computed against the usual 10s interval.
+	a := NewCounterAccumulator()
+	t0 := time.Unix(1000, 0)
+	a.Observe("aa:bb", DirectionTransmit, 1000, t0)
+
+	t1 := t0.Add(50 * time.Second)
+	rate, ok := a.Observe("aa:bb", DirectionTransmit, 26000, t1)
+	if !ok {
+		t.Fatalf("expected ok=true")
+	}
+	if rate.CumulativeBytes != 25000 {
+		t.Errorf("CumulativeBytes = %d, want 25000", rate.CumulativeBytes)
+	}
+	if rate.BytesPerSec != 500 {
+		t.Errorf("BytesPerSec = %v, want 500", rate.BytesPerSec)
+	}
+}
+
+func TestCounterAccumulatorWraparound(t *testing.T) {
+	// A 32-bit counter near its ceiling that wraps through zero should
+	// be treated as continuing to increase, not as a huge negative delta.
+	a := NewCounterAccumulator()
+	t0 := time.Unix(1000, 0)
+	nearMax := counter32Max - 1000
+	a.Observe("aa:bb", DirectionTransmit, nearMax, t0)
+
+	t1 := t0.Add(10 * time.Second)
+	rate, ok := a.Observe("aa:bb", DirectionTransmit, 4000, t1)
+	if !ok {
+		t.Fatalf("expected ok=true")
+	}
+	wantDelta := int64(1000 + 4000)
+	if rate.CumulativeBytes != wantDelta {
+		t.Errorf("CumulativeBytes = %d, want %d", rate.CumulativeBytes, wantDelta)
+	}
+}
+
+func TestCounterAccumulatorReset(t *testing.T) {
+	// A counter that drops but wasn't anywhere near the 32-bit ceiling
+	// indicates the device reset its counters (e.g. rebooted), not a
+	// wraparound; the post-reset reading is the delta.
+	a := NewCounterAccumulator()
+	t0 := time.Unix(1000, 0)
+	a.Observe("aa:bb", DirectionTransmit, 50000, t0)
+
+	t1 := t0.Add(10 * time.Second)
+	rate, ok := a.Observe("aa:bb", DirectionTransmit, 200, t1)
+	if !ok {
+		t.Fatalf("expected ok=true")
+	}
+	if rate.CumulativeBytes != 200 {
+		t.Errorf("CumulativeBytes = %d, want %d", rate.CumulativeBytes, 200)
+	}
+	if rate.BytesPerSec != 20 {
+		t.Errorf("BytesPerSec = %v, want 20", rate.BytesPerSec)
+	}
+}
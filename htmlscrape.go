@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// fetchAndParse issues an HTTP GET for url using client, honoring ctx for
+// cancellation and whatever timeout client is configured with, and parses
+// the response body as HTML.
+func fetchAndParse(ctx context.Context, client *http.Client, url string) (*html.Node, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return fetchRequest(client, req)
+}
+
+// fetchRequest executes req with client and parses the response body as
+// HTML, for callers that need to set headers (e.g. basic auth) on the
+// request before sending it.
+func fetchRequest(client *http.Client, req *http.Request) (*html.Node, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("HTTP response code: %v", resp.StatusCode)
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	node, err := html.Parse(strings.NewReader(string(data[:])))
+	if err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+type nodeConditionFunc func(*html.Node) bool
+
+func findDescendant(node *html.Node, conditionFunc nodeConditionFunc) *html.Node {
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if conditionFunc(child) {
+			return child
+		}
+		res := findDescendant(child, conditionFunc)
+		if res != nil {
+			return res
+		}
+	}
+	return nil
+}
+
+func findFollowupSibling(node *html.Node) *html.Node {
+	for sibling := node.NextSibling; sibling != nil; sibling = sibling.NextSibling {
+		if node.DataAtom == sibling.DataAtom {
+			return sibling
+		}
+	}
+	return nil
+}
+
+func getAttribute(node *html.Node, key string) string {
+	for _, attr := range node.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+func getInnerText(node *html.Node) string {
+	if node.Type == html.TextNode {
+		return node.Data
+	}
+	res := ""
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		res += getInnerText(child)
+	}
+	return res
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// scrapeDuration and scrapeErrors instrument the backend scrape loop
+// itself, independent of any one device's counters.
+var (
+	scrapeDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name: "network_monitor_scrape_duration_seconds",
+		Help: "Time spent fetching traffic counters from the router backend.",
+	})
+	scrapeErrors = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "network_monitor_scrape_errors_total",
+		Help: "Number of scrapes that failed to fetch or parse router data.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(scrapeDuration, scrapeErrors)
+}
+
+var (
+	transmitBytesDesc = prometheus.NewDesc(
+		"network_monitor_device_transmit_bytes_total",
+		"Cumulative bytes transmitted by a LAN device, as last reported by the router backend.",
+		[]string{"mac", "name"}, nil)
+	receiveBytesDesc = prometheus.NewDesc(
+		"network_monitor_device_receive_bytes_total",
+		"Cumulative bytes received by a LAN device, as last reported by the router backend.",
+		[]string{"mac", "name"}, nil)
+)
+
+// chartDataCollector implements prometheus.Collector by reading the latest
+// per-device counters out of ChartData under its mutex on every scrape, so
+// there's no separate metrics-specific copy of the data to keep in sync.
+type chartDataCollector struct {
+	chartData *ChartData
+}
+
+func newChartDataCollector(chartData *ChartData) *chartDataCollector {
+	return &chartDataCollector{chartData: chartData}
+}
+
+func (c *chartDataCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- transmitBytesDesc
+	ch <- receiveBytesDesc
+}
+
+func (c *chartDataCollector) Collect(ch chan<- prometheus.Metric) {
+	c.chartData.mu.Lock()
+	defer c.chartData.mu.Unlock()
+	for mac, device := range c.chartData.macAddressToDevice {
+		if len(device.TransmitBytes) == 0 || len(device.ReceiveBytes) == 0 {
+			continue
+		}
+		transmit := device.TransmitBytes[len(device.TransmitBytes)-1]
+		receive := device.ReceiveBytes[len(device.ReceiveBytes)-1]
+		ch <- prometheus.MustNewConstMetric(transmitBytesDesc, prometheus.CounterValue, float64(transmit), mac, device.DeviceName)
+		ch <- prometheus.MustNewConstMetric(receiveBytesDesc, prometheus.CounterValue, float64(receive), mac, device.DeviceName)
+	}
+}
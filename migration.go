@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"time"
+)
+
+// legacyDeviceData mirrors the pre-storage-layer DeviceData shape: raw
+// cumulative counters in parallel slices, one entry per fetch. The old
+// format never serialized MAC addresses (they lived only in the
+// unexported macAddressToDevice map), so DeviceName is the only key a
+// legacy dump has to offer; it's used in its place below.
+type legacyDeviceData struct {
+	DeviceName    string  `json:"DeviceName"`
+	TransmitBytes []int64 `json:"TransmitBytes"`
+	ReceiveBytes  []int64 `json:"ReceiveBytes"`
+}
+
+// legacyChartData mirrors the pre-storage-layer ChartData JSON shape as
+// previously served from /data.
+type legacyChartData struct {
+	Devices           []legacyDeviceData `json:"Devices"`
+	FetchMilliseconds []int64            `json:"FetchMilliseconds"`
+}
+
+// migrateLegacySnapshot imports a JSON dump of the old in-memory-only
+// ChartData format into store, so upgrading doesn't lose existing history.
+// It's safe to run more than once: samples are keyed by (mac, timestamp),
+// so re-importing just overwrites with identical values.
+func migrateLegacySnapshot(ctx context.Context, store Store, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var legacy legacyChartData
+	if err := json.NewDecoder(f).Decode(&legacy); err != nil {
+		return err
+	}
+
+	for _, device := range legacy.Devices {
+		if device.DeviceName == "" {
+			continue
+		}
+		n := len(device.TransmitBytes)
+		if len(device.ReceiveBytes) < n {
+			n = len(device.ReceiveBytes)
+		}
+		if len(legacy.FetchMilliseconds) < n {
+			n = len(legacy.FetchMilliseconds)
+		}
+		for i := 0; i < n; i++ {
+			sample := Sample{
+				Time:          time.UnixMilli(legacy.FetchMilliseconds[i]),
+				MACAddress:    device.DeviceName,
+				TransmitBytes: device.TransmitBytes[i],
+				ReceiveBytes:  device.ReceiveBytes[i],
+			}
+			if err := store.Record(ctx, sample); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
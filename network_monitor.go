@@ -1,191 +1,149 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
-	"fmt"
-	"golang.org/x/net/html"
-	"golang.org/x/net/html/atom"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
 	"strconv"
-	"strings"
-	"time"
 	"sync"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 var chartData *ChartData
-
-func fetchAndParse(url string) (*html.Node, error) {
-	resp, err := http.Get(url)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("HTTP response code: %v", resp.StatusCode)
-	}
-	data, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-	node, err := html.Parse(strings.NewReader(string(data[:])))
-	if err != nil {
-		return nil, err
-	}
-	return node, nil
-}
-
-type nodeConditionFunc func(*html.Node) bool
-
-func findDescendant(node *html.Node, conditionFunc nodeConditionFunc) *html.Node {
-	for child := node.FirstChild; child != nil; child = child.NextSibling {
-		if conditionFunc(child) {
-			return child
-		}
-		res := findDescendant(child, conditionFunc)
-		if res != nil {
-			return res
-		}
-	}
-	return nil
-}
-
-func findFollowupSibling(node *html.Node) *html.Node {
-	for sibling := node.NextSibling; sibling != nil; sibling = sibling.NextSibling {
-		if node.DataAtom == sibling.DataAtom {
-			return sibling
-		}
-	}
-	return nil
-}
-
-func getAttribute(node *html.Node, key string) string {
-	for _, attr := range node.Attr {
-		if attr.Key == key {
-			return attr.Val
-		}
-	}
-	return ""
-}
-
-func getInnerText(node *html.Node) string {
-	if node.Type == html.TextNode {
-		return node.Data
-	}
-	res := ""
-	for child := node.FirstChild; child != nil; child = child.NextSibling {
-		res += getInnerText(child)
-	}
-	return res
-}
+var historyStore Store
+var counters = NewCounterAccumulator()
 
 type DeviceData struct {
-	DeviceName    string
-	TransmitBytes []int64
-	ReceiveBytes  []int64
+	DeviceName          string
+	TransmitBytes       []int64
+	ReceiveBytes        []int64
+	TransmitBytesPerSec []float64
+	ReceiveBytesPerSec  []float64
 }
 
 type ChartData struct {
 	macAddressToDevice map[string]*DeviceData
 	Devices            []*DeviceData
 	FetchMilliseconds  []int64
-	mu sync.Mutex
+	mu                 sync.Mutex
 }
 
-func fetchDevices(chartData *ChartData) error {
-	doc, err := fetchAndParse("http://192.168.1.254/cgi-bin/devices.ha")
-	chartData.mu.Lock()
-	defer chartData.mu.Unlock()
-	if err != nil {
-		return err
-	}
-	table := findDescendant(doc, func(node *html.Node) bool {
-		return node.DataAtom == atom.Table && getAttribute(node, "summary") == "This table displays info for each LAN-side device"
-	})
-	if table == nil {
-		return nil
-	}
-	for tr := findDescendant(table, func(node *html.Node) bool {
-		return node.DataAtom == atom.Tr
-	}); tr != nil; tr = findFollowupSibling(tr) {
-		th := findDescendant(tr, func(node *html.Node) bool {
-			return node.DataAtom == atom.Th && getInnerText(node) == "MAC Address"
-		})
-		if th == nil {
+// mergeDevices adds any devices in infos that chartData doesn't already
+// know about, keyed by MAC address.
+func (c *ChartData) mergeDevices(infos []DeviceInfo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, info := range infos {
+		if _, ok := c.macAddressToDevice[info.MACAddress]; ok {
 			continue
 		}
-		td := findDescendant(tr, func(node *html.Node) bool {
-			return node.DataAtom == atom.Td
-		})
-		if td == nil {
-			continue
+		deviceData := &DeviceData{
+			DeviceName:          info.Name,
+			TransmitBytes:       []int64{},
+			ReceiveBytes:        []int64{},
+			TransmitBytesPerSec: []float64{},
+			ReceiveBytesPerSec:  []float64{},
 		}
-		macAddress := strings.Trim(getInnerText(td), " \t\n")
-		if _, ok := chartData.macAddressToDevice[macAddress]; ok {
+		c.macAddressToDevice[info.MACAddress] = deviceData
+		c.Devices = append(c.Devices, deviceData)
+	}
+}
+
+// applyTrafficSamples records one fetch's worth of samples against their
+// matching devices, both in the in-memory ChartData (for the existing
+// full-history /data response) and, if store is non-nil, in the durable
+// history store. Samples for MAC addresses that haven't been seen by
+// mergeDevices yet are dropped.
+func (c *ChartData) applyTrafficSamples(ctx context.Context, store Store, samples []TrafficSample) []Rate {
+	now := time.Now()
+	var rates []Rate
+	c.mu.Lock()
+	c.FetchMilliseconds = append(c.FetchMilliseconds, now.UnixNano()/1000000)
+	for _, sample := range samples {
+		deviceData, ok := c.macAddressToDevice[sample.MACAddress]
+		if !ok {
 			continue
 		}
-		tr = findFollowupSibling(tr)
-		if tr == nil {
-			continue
+		deviceData.TransmitBytes = append(deviceData.TransmitBytes, sample.TransmitBytes)
+		deviceData.ReceiveBytes = append(deviceData.ReceiveBytes, sample.ReceiveBytes)
+
+		transmitRate, transmitOK := counters.Observe(sample.MACAddress, DirectionTransmit, sample.TransmitBytes, now)
+		receiveRate, receiveOK := counters.Observe(sample.MACAddress, DirectionReceive, sample.ReceiveBytes, now)
+		deviceData.TransmitBytesPerSec = append(deviceData.TransmitBytesPerSec, transmitRate.BytesPerSec)
+		deviceData.ReceiveBytesPerSec = append(deviceData.ReceiveBytesPerSec, receiveRate.BytesPerSec)
+		if transmitOK {
+			rates = append(rates, transmitRate)
 		}
-		td = findDescendant(tr, func(node *html.Node) bool {
-			return node.DataAtom == atom.Td
-		})
-		if td == nil {
-			continue
+		if receiveOK {
+			rates = append(rates, receiveRate)
 		}
-		deviceData := &DeviceData{
-			DeviceName:    strings.ReplaceAll(strings.Trim(getInnerText(td), " \t\n"), "\n", ""),
-			TransmitBytes: []int64{},
-			ReceiveBytes:  []int64{}}
-		chartData.macAddressToDevice[macAddress] = deviceData
-		chartData.Devices = append(chartData.Devices, deviceData)
 	}
-	return nil
+	c.mu.Unlock()
+
+	if store != nil {
+		for _, sample := range samples {
+			if err := store.Record(ctx, Sample{
+				Time:          now,
+				MACAddress:    sample.MACAddress,
+				TransmitBytes: sample.TransmitBytes,
+				ReceiveBytes:  sample.ReceiveBytes,
+			}); err != nil {
+				log.Print(err)
+			}
+		}
+	}
+	return rates
 }
 
-func fetchLanStatistics(chartData *ChartData) error {
-	doc, err := fetchAndParse("http://192.168.1.254/cgi-bin/lanstatistics.ha")
-	chartData.mu.Lock()
-	defer chartData.mu.Unlock()
-	chartData.FetchMilliseconds = append(chartData.FetchMilliseconds, time.Now().UnixNano()/1000000)
+// recoverHistory preloads chartData with each device's most recent day of
+// samples from store, so the chart has continuity across a restart instead
+// of starting empty. It doesn't reconstruct FetchMilliseconds, so the
+// recovered TransmitBytes/ReceiveBytes entries won't line up by index with
+// it until enough new samples have been recorded; new code should prefer
+// the /data?mac=...&from=...&to=... range endpoint, which timestamps each
+// sample directly.
+func (c *ChartData) recoverHistory(ctx context.Context, store Store) error {
+	macs, err := store.Devices(ctx)
 	if err != nil {
 		return err
 	}
-	table := findDescendant(doc, func(node *html.Node) bool {
-		return node.DataAtom == atom.Table && getAttribute(node, "summary") == "Wi-Fi Client Connection Statistics Table"
-	})
-	if table == nil {
-		return fmt.Errorf("Table not found")
-	}
-	tr := findDescendant(table, func(node *html.Node) bool {
-		return node.DataAtom == atom.Tr
-	})
-	if tr == nil {
-		return fmt.Errorf("Tr not found")
-	}
-	for tr = findFollowupSibling(tr); tr != nil; tr = findFollowupSibling(tr) {
-		columnIndex := 0
-		var deviceData *DeviceData
-		for td := findDescendant(tr, func(node *html.Node) bool {
-			return node.DataAtom == atom.Td
-		}); td != nil; td = findFollowupSibling(td) {
-			if columnIndex == 0 {
-				macAddress := strings.Trim(getInnerText(td), " \t\n")
-				deviceData = chartData.macAddressToDevice[macAddress]
-			}
-			if deviceData != nil && columnIndex == 7 {
-				if bytes, err := strconv.ParseInt(strings.Trim(getInnerText(td), " \t\n"), 10, 32); err == nil {
-					deviceData.TransmitBytes = append(deviceData.TransmitBytes, bytes)
-				}
-			}
-			if deviceData != nil && columnIndex == 8 {
-				if bytes, err := strconv.ParseInt(strings.Trim(getInnerText(td), " \t\n"), 10, 32); err == nil {
-					deviceData.ReceiveBytes = append(deviceData.TransmitBytes, bytes)
-				}
+	from := time.Now().Add(-24 * time.Hour)
+	to := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, mac := range macs {
+		deviceData, ok := c.macAddressToDevice[mac]
+		if !ok {
+			deviceData = &DeviceData{
+				DeviceName:          mac,
+				TransmitBytes:       []int64{},
+				ReceiveBytes:        []int64{},
+				TransmitBytesPerSec: []float64{},
+				ReceiveBytesPerSec:  []float64{},
 			}
-			columnIndex++
+			c.macAddressToDevice[mac] = deviceData
+			c.Devices = append(c.Devices, deviceData)
+		}
+		samples, err := store.Query(ctx, mac, from, to, 0)
+		if err != nil {
+			return err
+		}
+		for _, sample := range samples {
+			deviceData.TransmitBytes = append(deviceData.TransmitBytes, sample.TransmitBytes)
+			deviceData.ReceiveBytes = append(deviceData.ReceiveBytes, sample.ReceiveBytes)
+
+			transmitRate, _ := counters.Observe(mac, DirectionTransmit, sample.TransmitBytes, sample.Time)
+			receiveRate, _ := counters.Observe(mac, DirectionReceive, sample.ReceiveBytes, sample.Time)
+			deviceData.TransmitBytesPerSec = append(deviceData.TransmitBytesPerSec, transmitRate.BytesPerSec)
+			deviceData.ReceiveBytesPerSec = append(deviceData.ReceiveBytesPerSec, receiveRate.BytesPerSec)
 		}
 	}
 	return nil
@@ -193,14 +151,19 @@ func fetchLanStatistics(chartData *ChartData) error {
 
 func indexHandler(w http.ResponseWriter, r *http.Request) {
 	if data, err := ioutil.ReadFile("index.html"); err == nil {
-    w.Write(data)
-  } else {
+		w.Write(data)
+	} else {
 		w.WriteHeader(500)
 		w.Write([]byte(err.Error()))
 	}
 }
 
 func dataHandler(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("from") != "" || q.Get("to") != "" || q.Get("mac") != "" {
+		rangeDataHandler(w, r)
+		return
+	}
 	chartData.mu.Lock()
 	data, err := json.Marshal(chartData)
 	chartData.mu.Unlock()
@@ -212,26 +175,165 @@ func dataHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// rangeDataHandler serves /data?mac=...&from=...&to=...&resolution=...,
+// where from/to are Unix milliseconds and resolution is a duration in
+// milliseconds (defaulting to 0, i.e. raw samples).
+func rangeDataHandler(w http.ResponseWriter, r *http.Request) {
+	if historyStore == nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("history store not configured"))
+		return
+	}
+	q := r.URL.Query()
+	mac := q.Get("mac")
+	if mac == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("mac is required"))
+		return
+	}
+	fromMS, err := strconv.ParseInt(q.Get("from"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("from must be a Unix millisecond timestamp"))
+		return
+	}
+	toMS, err := strconv.ParseInt(q.Get("to"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte("to must be a Unix millisecond timestamp"))
+		return
+	}
+	resolutionMS, _ := strconv.ParseInt(q.Get("resolution"), 10, 64)
+
+	samples, err := historyStore.Query(r.Context(), mac, time.UnixMilli(fromMS), time.UnixMilli(toMS), time.Duration(resolutionMS)*time.Millisecond)
+	if err != nil {
+		w.WriteHeader(500)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	data, err := json.Marshal(samples)
+	if err != nil {
+		w.WriteHeader(500)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	w.Write(data)
+}
+
+var alertEngine *AlertEngine
+
+func alertsHandler(w http.ResponseWriter, r *http.Request) {
+	data, err := json.Marshal(alertEngine.Alerts())
+	if err != nil {
+		w.WriteHeader(500)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	w.Write(data)
+}
+
 func main() {
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
 
+	cfg, err := configFromFlags()
+	if err != nil {
+		log.Fatal(err)
+	}
+	backend, err := NewRouterBackend(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	store, err := NewSQLiteStore(cfg.DBPath, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer store.Close()
+	historyStore = store
+
 	chartData = &ChartData{
 		macAddressToDevice: make(map[string]*DeviceData),
 		Devices:            []*DeviceData{},
 		FetchMilliseconds:  []int64{}}
-	if err := fetchDevices(chartData); err != nil {
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if cfg.LegacySnapshotPath != "" {
+		if err := migrateLegacySnapshot(ctx, store, cfg.LegacySnapshotPath); err != nil {
+			log.Print(err)
+		}
+	}
+
+	devices, err := backend.ListDevices(ctx)
+	if err != nil {
 		log.Fatal(err)
 	}
+	chartData.mergeDevices(devices)
+	if err := chartData.recoverHistory(ctx, store); err != nil {
+		log.Print(err)
+	}
+
+	prometheus.MustRegister(newChartDataCollector(chartData))
+	alertEngine = NewAlertEngine(cfg.Alerts.Rules, buildNotifiers(cfg.Alerts))
+
+	go func() {
+		for {
+			start := time.Now()
+			samples, err := backend.SampleTraffic(ctx)
+			scrapeDuration.Observe(time.Since(start).Seconds())
+			if err != nil {
+				scrapeErrors.Inc()
+				log.Print(err)
+			} else {
+				rates := chartData.applyTrafficSamples(ctx, store, samples)
+				alertEngine.Evaluate(ctx, start, rates)
+			}
+			if sleepOrDone(ctx, 10*time.Second) {
+				return
+			}
+		}
+	}()
 	go func() {
 		for {
-		  if err := fetchLanStatistics(chartData); err != nil {
+			if sleepOrDone(ctx, time.Hour) {
+				return
+			}
+			if err := store.ApplyRetention(ctx); err != nil {
 				log.Print(err)
 			}
-		  time.Sleep(10 * time.Second)
-	  }
+		}
 	}()
+
 	http.HandleFunc("/", indexHandler)
 	http.HandleFunc("/data", dataHandler)
+	http.HandleFunc("/alerts", alertsHandler)
+	http.Handle("/metrics", promhttp.Handler())
+	server := &http.Server{Addr: ":8080"}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Print(err)
+		}
+	}()
 	log.Print("Serving request from http://localhost:8080")
-  log.Print(http.ListenAndServe(":8080", nil))
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Print(err)
+	}
+}
+
+// sleepOrDone waits for d or for ctx to be cancelled, whichever comes
+// first, returning true if ctx was cancelled so the caller's loop can
+// exit promptly instead of finishing out its sleep.
+func sleepOrDone(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return false
+	case <-ctx.Done():
+		return true
+	}
 }
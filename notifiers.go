@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/smtp"
+)
+
+// Notifier dispatches an Alert somewhere outside the process: a webhook, an
+// email, or (for NoopNotifier) just the process log.
+type Notifier interface {
+	Notify(ctx context.Context, alert Alert) error
+}
+
+// WebhookConfig configures a generic HTTP webhook notifier.
+type WebhookConfig struct {
+	// URL receives a POST with the alert JSON-encoded as the body.
+	URL string `json:"url"`
+}
+
+// WebhookNotifier POSTs a JSON-encoded Alert to a configured URL.
+type WebhookNotifier struct {
+	cfg    WebhookConfig
+	client *http.Client
+}
+
+// NewWebhookNotifier returns a Notifier that POSTs alerts to cfg.URL.
+func NewWebhookNotifier(cfg WebhookConfig) *WebhookNotifier {
+	return &WebhookNotifier{cfg: cfg, client: &http.Client{Timeout: defaultFetchTimeout}}
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, alert Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook notifier: HTTP response code: %v", resp.StatusCode)
+	}
+	return nil
+}
+
+// SMTPConfig configures the email notifier.
+type SMTPConfig struct {
+	Host     string   `json:"host"`
+	Port     int      `json:"port"`
+	Username string   `json:"username"`
+	Password string   `json:"password"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+}
+
+// SMTPNotifier emails alerts via a configured SMTP relay.
+type SMTPNotifier struct {
+	cfg SMTPConfig
+}
+
+// NewSMTPNotifier returns a Notifier that sends email through cfg's SMTP
+// server.
+func NewSMTPNotifier(cfg SMTPConfig) *SMTPNotifier {
+	return &SMTPNotifier{cfg: cfg}
+}
+
+func (n *SMTPNotifier) Notify(ctx context.Context, alert Alert) error {
+	addr := fmt.Sprintf("%s:%d", n.cfg.Host, n.cfg.Port)
+	var auth smtp.Auth
+	if n.cfg.Username != "" {
+		auth = smtp.PlainAuth("", n.cfg.Username, n.cfg.Password, n.cfg.Host)
+	}
+	subject := fmt.Sprintf("network_monitor alert: %s", alert.RuleName)
+	msg := fmt.Sprintf("Subject: %s\r\n\r\n%s\r\n", subject, alert.Message)
+	return smtp.SendMail(addr, auth, n.cfg.From, n.cfg.To, []byte(msg))
+}
+
+// NoopNotifier just logs the alert; it's the default notifier and is
+// useful for rules that only need to show up in /alerts without also
+// paging anyone.
+type NoopNotifier struct{}
+
+func (NoopNotifier) Notify(ctx context.Context, alert Alert) error {
+	log.Printf("alert: %s (%s): %s", alert.RuleName, alert.MACAddress, alert.Message)
+	return nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// DeviceInfo describes a single LAN-side device as reported by a router
+// backend.
+type DeviceInfo struct {
+	MACAddress string
+	Name       string
+}
+
+// TrafficSample is one reading of a device's cumulative transmit/receive
+// counters, as reported by a router backend at a point in time.
+type TrafficSample struct {
+	MACAddress    string
+	TransmitBytes int64
+	ReceiveBytes  int64
+}
+
+// RouterBackend abstracts over the different ways a router or gateway can be
+// queried for connected devices and their traffic counters, so the rest of
+// the monitor doesn't need to know whether it's scraping HTML, walking SNMP,
+// querying a UPnP IGD, or browsing mDNS.
+type RouterBackend interface {
+	// ListDevices returns the set of devices currently known to the router.
+	ListDevices(ctx context.Context) ([]DeviceInfo, error)
+	// SampleTraffic returns one cumulative traffic reading per device the
+	// backend currently has counters for.
+	SampleTraffic(ctx context.Context) ([]TrafficSample, error)
+}
+
+// BackendKind identifies which RouterBackend implementation a config entry
+// selects.
+type BackendKind string
+
+const (
+	BackendATTGateway BackendKind = "att-gateway"
+	BackendSNMP       BackendKind = "snmp"
+	BackendUPnP       BackendKind = "upnp"
+	BackendMDNS       BackendKind = "mdns"
+)
+
+// NewRouterBackend constructs the RouterBackend selected by cfg.Backend,
+// using the matching sub-config for credentials and connection details. If
+// cfg.MDNS.Enabled, the result is wrapped in an MDNSNamingBackend overlay
+// that enriches device names via mDNS.
+func NewRouterBackend(cfg *Config) (RouterBackend, error) {
+	backend, err := newSelectedBackend(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.MDNS.Enabled {
+		backend = NewMDNSNamingBackend(backend, cfg.MDNS)
+	}
+	return backend, nil
+}
+
+func newSelectedBackend(cfg *Config) (RouterBackend, error) {
+	switch cfg.Backend {
+	case "", BackendATTGateway:
+		return NewATTGatewayBackend(cfg.ATTGateway), nil
+	case BackendSNMP:
+		return NewSNMPBackend(cfg.SNMP)
+	case BackendUPnP:
+		return NewUPnPBackend(cfg.UPnP)
+	case BackendMDNS:
+		return nil, fmt.Errorf("mdns is a naming-only overlay and has no traffic counters; it cannot be used as the sole backend")
+	default:
+		return nil, fmt.Errorf("unknown router backend %q", cfg.Backend)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// Sample is one persisted (timestamp, device, counters) reading.
+type Sample struct {
+	Time          time.Time
+	MACAddress    string
+	TransmitBytes int64
+	ReceiveBytes  int64
+}
+
+// RetentionTier defines how long samples at a given resolution are kept
+// before being rolled up into the next coarser tier (or dropped entirely,
+// for the last tier). Tiers must be given from finest to coarsest.
+type RetentionTier struct {
+	Resolution time.Duration
+	Retention  time.Duration
+}
+
+// defaultRetentionTiers keeps raw ~10s samples for a day, 1-minute rollups
+// for a month, and 1-hour rollups for a year.
+var defaultRetentionTiers = []RetentionTier{
+	{Resolution: 0, Retention: 24 * time.Hour},
+	{Resolution: time.Minute, Retention: 30 * 24 * time.Hour},
+	{Resolution: time.Hour, Retention: 365 * 24 * time.Hour},
+}
+
+// Store persists per-device traffic samples and serves range queries over
+// them, downsampling older data according to a configured retention
+// schedule. The zero resolution tier holds raw, as-fetched samples.
+type Store interface {
+	// Record appends one raw sample for the given device.
+	Record(ctx context.Context, sample Sample) error
+	// Devices returns the MAC addresses the store has any history for.
+	Devices(ctx context.Context) ([]string, error)
+	// Query returns samples for mac in [from, to), read from the
+	// coarsest tier whose resolution is <= the requested resolution.
+	Query(ctx context.Context, mac string, from, to time.Time, resolution time.Duration) ([]Sample, error)
+	// ApplyRetention rolls up and prunes samples per the configured
+	// retention tiers. Intended to be called periodically, e.g. hourly.
+	ApplyRetention(ctx context.Context) error
+	Close() error
+}
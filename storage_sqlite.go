@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// SQLiteStore implements Store on top of a single SQLite file. Samples are
+// stored in one table, with resolutionMS distinguishing raw samples (0)
+// from rolled-up tiers (the bucket width in milliseconds).
+type SQLiteStore struct {
+	db    *sql.DB
+	tiers []RetentionTier
+}
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS samples (
+	mac TEXT NOT NULL,
+	resolution_ms INTEGER NOT NULL,
+	ts_ms INTEGER NOT NULL,
+	transmit_bytes INTEGER NOT NULL,
+	receive_bytes INTEGER NOT NULL,
+	PRIMARY KEY (mac, resolution_ms, ts_ms)
+);
+CREATE INDEX IF NOT EXISTS idx_samples_lookup ON samples(mac, resolution_ms, ts_ms);
+`
+
+// NewSQLiteStore opens (creating if necessary) a SQLite database at path
+// and ensures its schema exists. tiers configures the retention schedule
+// used by ApplyRetention; a nil slice falls back to defaultRetentionTiers.
+func NewSQLiteStore(path string, tiers []RetentionTier) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if tiers == nil {
+		tiers = defaultRetentionTiers
+	}
+	return &SQLiteStore{db: db, tiers: tiers}, nil
+}
+
+func (s *SQLiteStore) Record(ctx context.Context, sample Sample) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT OR REPLACE INTO samples (mac, resolution_ms, ts_ms, transmit_bytes, receive_bytes) VALUES (?, 0, ?, ?, ?)`,
+		sample.MACAddress, sample.Time.UnixMilli(), sample.TransmitBytes, sample.ReceiveBytes)
+	return err
+}
+
+func (s *SQLiteStore) Devices(ctx context.Context) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT DISTINCT mac FROM samples`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var macs []string
+	for rows.Next() {
+		var mac string
+		if err := rows.Scan(&mac); err != nil {
+			return nil, err
+		}
+		macs = append(macs, mac)
+	}
+	return macs, rows.Err()
+}
+
+// tierFor picks the coarsest configured tier whose resolution is still
+// fine enough to satisfy the requested resolution.
+func (s *SQLiteStore) tierFor(resolution time.Duration) RetentionTier {
+	best := s.tiers[0]
+	for _, tier := range s.tiers {
+		if tier.Resolution <= resolution {
+			best = tier
+		}
+	}
+	return best
+}
+
+func (s *SQLiteStore) Query(ctx context.Context, mac string, from, to time.Time, resolution time.Duration) ([]Sample, error) {
+	tier := s.tierFor(resolution)
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT ts_ms, transmit_bytes, receive_bytes FROM samples
+		 WHERE mac = ? AND resolution_ms = ? AND ts_ms >= ? AND ts_ms < ?
+		 ORDER BY ts_ms ASC`,
+		mac, tier.Resolution.Milliseconds(), from.UnixMilli(), to.UnixMilli())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var samples []Sample
+	for rows.Next() {
+		var tsMS, transmit, receive int64
+		if err := rows.Scan(&tsMS, &transmit, &receive); err != nil {
+			return nil, err
+		}
+		samples = append(samples, Sample{
+			Time:          time.UnixMilli(tsMS),
+			MACAddress:    mac,
+			TransmitBytes: transmit,
+			ReceiveBytes:  receive,
+		})
+	}
+	return samples, rows.Err()
+}
+
+// ApplyRetention rolls samples from each tier into the next coarser one
+// once they age past that tier's retention window, then deletes the
+// now-redundant finer-grained rows. The last tier's aged-out rows are
+// simply dropped.
+func (s *SQLiteStore) ApplyRetention(ctx context.Context) error {
+	now := time.Now()
+	for i, tier := range s.tiers {
+		cutoff := now.Add(-tier.Retention).UnixMilli()
+		if i+1 < len(s.tiers) {
+			next := s.tiers[i+1]
+			if err := s.rollup(ctx, tier, next, cutoff); err != nil {
+				return fmt.Errorf("rollup %v -> %v: %w", tier.Resolution, next.Resolution, err)
+			}
+		} else {
+			if _, err := s.db.ExecContext(ctx,
+				`DELETE FROM samples WHERE resolution_ms = ? AND ts_ms < ?`,
+				tier.Resolution.Milliseconds(), cutoff); err != nil {
+				return fmt.Errorf("prune %v: %w", tier.Resolution, err)
+			}
+		}
+	}
+	return nil
+}
+
+// rollup keeps, for each `from`-tier bucket older than cutoff, the last
+// sample in that bucket (transmit_bytes/receive_bytes are monotonic
+// cumulative counters, so the last reading is the bucket's representative
+// value; averaging them would pull a bucket's value below later raw
+// samples and could make a derived rate briefly negative at tier
+// boundaries) into a `to`-tier row, then deletes the rows that were rolled
+// up.
+func (s *SQLiteStore) rollup(ctx context.Context, from, to RetentionTier, cutoff int64) error {
+	bucketMS := to.Resolution.Milliseconds()
+	if bucketMS == 0 {
+		return fmt.Errorf("rollup target resolution must be > 0")
+	}
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT bucketed.mac, bucketed.bucket, latest.transmit_bytes, latest.receive_bytes
+		 FROM (
+			 SELECT mac, (ts_ms / ?) * ? AS bucket, MAX(ts_ms) AS max_ts
+			 FROM samples WHERE resolution_ms = ? AND ts_ms < ?
+			 GROUP BY mac, bucket
+		 ) bucketed
+		 JOIN samples latest
+			 ON latest.mac = bucketed.mac AND latest.resolution_ms = ? AND latest.ts_ms = bucketed.max_ts`,
+		bucketMS, bucketMS, from.Resolution.Milliseconds(), cutoff, from.Resolution.Milliseconds())
+	if err != nil {
+		return err
+	}
+	type bucketRow struct {
+		mac            string
+		bucketMS       int64
+		transmit, recv int64
+	}
+	var buckets []bucketRow
+	for rows.Next() {
+		var b bucketRow
+		if err := rows.Scan(&b.mac, &b.bucketMS, &b.transmit, &b.recv); err != nil {
+			rows.Close()
+			return err
+		}
+		buckets = append(buckets, b)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	for _, b := range buckets {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT OR REPLACE INTO samples (mac, resolution_ms, ts_ms, transmit_bytes, receive_bytes) VALUES (?, ?, ?, ?, ?)`,
+			b.mac, bucketMS, b.bucketMS, b.transmit, b.recv); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM samples WHERE resolution_ms = ? AND ts_ms < ?`,
+		from.Resolution.Milliseconds(), cutoff); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}